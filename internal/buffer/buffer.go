@@ -0,0 +1,425 @@
+// Package buffer implements the text storage for dm as a piece table:
+// an immutable original buffer plus an append-only add buffer, with
+// the pieces (source, offset, length) ordered by an augmented treap
+// instead of a flat slice. Edits only touch O(log P) tree nodes, and
+// line lookups use per-piece newline counts instead of rescanning the
+// document, so neither scales with document size the way a
+// copy-on-write [][]rune or a linearly shifted line index would.
+//
+// The piece table is addressed by rune offset, so Open still reads a
+// file's full contents into memory up front and decodes it to []rune;
+// this package doesn't make opening a large file any cheaper than
+// os.ReadFile would, only editing and navigating one afterward.
+package buffer
+
+import (
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// priorityRand is the treap's source of balancing priorities. A single
+// shared source is fine: Buffer isn't used concurrently, and the
+// quality of the randomness only affects tree balance, not correctness.
+var priorityRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func randPriority() uint32 {
+	return priorityRand.Uint32()
+}
+
+type piece struct {
+	fromAdd bool
+	start   int
+	length  int
+}
+
+// node is one piece in the treap that orders pieces by document
+// position. size and newlines are subtree aggregates (rune length and
+// newline count); ownNL is just this node's own piece, cached so
+// re-deriving the aggregates after a rotation (pull) doesn't need to
+// re-scan the underlying source.
+type node struct {
+	p           piece
+	left, right *node
+	priority    uint32
+	ownNL       int
+	size        int
+	newlines    int
+}
+
+func size(n *node) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func newlines(n *node) int {
+	if n == nil {
+		return 0
+	}
+	return n.newlines
+}
+
+// pull recomputes n's subtree aggregates from its children and its own
+// piece. It's called after any change to n.left, n.right, or n.p.
+func pull(n *node) {
+	if n == nil {
+		return
+	}
+	n.size = n.p.length + size(n.left) + size(n.right)
+	n.newlines = n.ownNL + newlines(n.left) + newlines(n.right)
+}
+
+// merge joins two treaps where every element of l comes before every
+// element of r, restoring the heap property on priority.
+func merge(l, r *node) *node {
+	if l == nil {
+		return r
+	}
+	if r == nil {
+		return l
+	}
+	if l.priority > r.priority {
+		l.right = merge(l.right, r)
+		pull(l)
+		return l
+	}
+	r.left = merge(l, r.left)
+	pull(r)
+	return r
+}
+
+// Buffer is a piece-table document addressed by rune offset.
+type Buffer struct {
+	original []rune
+	add      []rune
+	root     *node
+	length   int
+
+	originalNewlines []int // sorted offsets of '\n' within original
+	addNewlines      []int // sorted offsets of '\n' within add
+}
+
+// New builds a Buffer over initial content.
+func New(initial []rune) *Buffer {
+	b := &Buffer{original: initial, originalNewlines: newlineOffsets(initial)}
+	if len(initial) > 0 {
+		b.root = b.newNode(piece{fromAdd: false, start: 0, length: len(initial)})
+		b.length = len(initial)
+	}
+	return b
+}
+
+// Open loads path as the original buffer for a new Buffer.
+func Open(path string) (*Buffer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New([]rune(string(data))), nil
+}
+
+func newlineOffsets(s []rune) []int {
+	var offsets []int
+	for i, r := range s {
+		if r == '\n' {
+			offsets = append(offsets, i)
+		}
+	}
+	return offsets
+}
+
+// pieceNewlines counts the newlines p covers by binary-searching the
+// source's newline-offset index instead of scanning p's runes, so it
+// stays O(log n) even for a piece spanning the whole original file.
+func (b *Buffer) pieceNewlines(p piece) int {
+	offsets := b.originalNewlines
+	if p.fromAdd {
+		offsets = b.addNewlines
+	}
+	lo := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= p.start })
+	hi := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= p.start+p.length })
+	return hi - lo
+}
+
+func (b *Buffer) newNode(p piece) *node {
+	nl := b.pieceNewlines(p)
+	return &node{p: p, priority: randPriority(), ownNL: nl, size: p.length, newlines: nl}
+}
+
+// split divides the treap rooted at n into (left, right) where left
+// holds the first k runes and right holds the rest, splitting a single
+// piece in two when k falls inside it. O(log P) plus the O(log n)
+// newline recount for the (at most two) pieces it has to cut.
+func (b *Buffer) split(n *node, k int) (*node, *node) {
+	if n == nil {
+		return nil, nil
+	}
+	leftSize := size(n.left)
+	switch {
+	case k < leftSize:
+		l, r := b.split(n.left, k)
+		n.left = r
+		pull(n)
+		return l, n
+	case k > leftSize+n.p.length:
+		l, r := b.split(n.right, k-leftSize-n.p.length)
+		n.right = l
+		pull(n)
+		return n, r
+	case k == leftSize:
+		l := n.left
+		n.left = nil
+		pull(n)
+		return l, n
+	case k == leftSize+n.p.length:
+		r := n.right
+		n.right = nil
+		pull(n)
+		return n, r
+	default:
+		within := k - leftSize
+		leftPiece := piece{fromAdd: n.p.fromAdd, start: n.p.start, length: within}
+		rightPiece := piece{fromAdd: n.p.fromAdd, start: n.p.start + within, length: n.p.length - within}
+		leftNode := &node{p: leftPiece, left: n.left, priority: n.priority, ownNL: b.pieceNewlines(leftPiece)}
+		pull(leftNode)
+		rightNode := &node{p: rightPiece, right: n.right, priority: n.priority, ownNL: b.pieceNewlines(rightPiece)}
+		pull(rightNode)
+		return leftNode, rightNode
+	}
+}
+
+// Len returns the document length in runes.
+func (b *Buffer) Len() int {
+	return b.length
+}
+
+// LineCount returns the number of lines in the document. A document
+// with no trailing newline still has at least one line.
+func (b *Buffer) LineCount() int {
+	return newlines(b.root) + 1
+}
+
+// nthNewline returns the absolute offset just past the j-th
+// (0-indexed) newline in the subtree rooted at n, which starts at
+// document position pos; that is the start of line j+1.
+func (b *Buffer) nthNewline(n *node, pos, j int) int {
+	leftNL := newlines(n.left)
+	if j < leftNL {
+		return b.nthNewline(n.left, pos, j)
+	}
+	j -= leftNL
+	pieceStart := pos + size(n.left)
+	if j < n.ownNL {
+		offsets := b.originalNewlines
+		if n.p.fromAdd {
+			offsets = b.addNewlines
+		}
+		lo := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= n.p.start })
+		srcOffset := offsets[lo+j]
+		return pieceStart + (srcOffset - n.p.start) + 1
+	}
+	j -= n.ownNL
+	return b.nthNewline(n.right, pieceStart+n.p.length, j)
+}
+
+// LineStart returns the offset of the first rune of line n. n ==
+// LineCount() is valid and returns Len().
+func (b *Buffer) LineStart(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	if n-1 >= newlines(b.root) {
+		return b.length
+	}
+	return b.nthNewline(b.root, 0, n-1)
+}
+
+// lineEnd returns the offset just past line n's content, excluding its
+// trailing newline.
+func (b *Buffer) lineEnd(n int) int {
+	if n+1 >= b.LineCount() {
+		return b.length
+	}
+	return b.LineStart(n+1) - 1
+}
+
+// Line materializes the runes of line n (without its trailing
+// newline).
+func (b *Buffer) Line(n int) []rune {
+	return b.RuneRange(b.LineStart(n), b.lineEnd(n))
+}
+
+// newlinesBefore counts the newlines at a position strictly before
+// offset in the subtree rooted at n (starting at document position
+// pos), which is exactly the 0-indexed line number offset falls on.
+func (b *Buffer) newlinesBefore(n *node, pos, offset int) int {
+	if n == nil {
+		return 0
+	}
+	leftSize := size(n.left)
+	pieceStart := pos + leftSize
+	if offset <= pieceStart {
+		return b.newlinesBefore(n.left, pos, offset)
+	}
+	count := newlines(n.left)
+	pieceEnd := pieceStart + n.p.length
+	if offset >= pieceEnd {
+		count += n.ownNL
+		return count + b.newlinesBefore(n.right, pieceEnd, offset)
+	}
+	offsets := b.originalNewlines
+	if n.p.fromAdd {
+		offsets = b.addNewlines
+	}
+	lo := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= n.p.start })
+	hi := sort.Search(len(offsets), func(i int) bool { return offsets[i] >= n.p.start+(offset-pieceStart) })
+	return count + (hi - lo)
+}
+
+// LineCol converts an absolute rune offset into a (line, column) pair.
+func (b *Buffer) LineCol(offset int) (line, col int) {
+	line = b.newlinesBefore(b.root, 0, offset)
+	return line, offset - b.LineStart(line)
+}
+
+// collect appends the runes of [start, end) within the subtree rooted
+// at n (starting at document position pos) to out, touching only the
+// pieces that overlap the range.
+func (b *Buffer) collect(n *node, pos, start, end int, out *[]rune) {
+	if n == nil || start >= end {
+		return
+	}
+	leftSize := size(n.left)
+	pieceStart := pos + leftSize
+	if start < pieceStart {
+		b.collect(n.left, pos, start, end, out)
+	}
+	pieceEnd := pieceStart + n.p.length
+	if start < pieceEnd && end > pieceStart {
+		src := b.original
+		if n.p.fromAdd {
+			src = b.add
+		}
+		from := n.p.start
+		if start > pieceStart {
+			from += start - pieceStart
+		}
+		to := n.p.start + n.p.length
+		if pieceEnd > end {
+			to -= pieceEnd - end
+		}
+		*out = append(*out, src[from:to]...)
+	}
+	if end > pieceEnd {
+		b.collect(n.right, pieceEnd, start, end, out)
+	}
+}
+
+// RuneRange materializes the runes in [start, end), touching only the
+// pieces that overlap the range.
+func (b *Buffer) RuneRange(start, end int) []rune {
+	if end <= start {
+		return nil
+	}
+	out := make([]rune, 0, end-start)
+	b.collect(b.root, 0, start, end, &out)
+	return out
+}
+
+// String materializes the whole document, for save().
+func (b *Buffer) String() string {
+	return string(b.RuneRange(0, b.length))
+}
+
+// runeAt returns the rune at offset within the subtree rooted at n,
+// which starts at document position pos.
+func (b *Buffer) runeAt(n *node, pos, offset int) rune {
+	leftSize := size(n.left)
+	pieceStart := pos + leftSize
+	if offset < pieceStart {
+		return b.runeAt(n.left, pos, offset)
+	}
+	if offset < pieceStart+n.p.length {
+		src := b.original
+		if n.p.fromAdd {
+			src = b.add
+		}
+		return src[n.p.start+offset-pieceStart]
+	}
+	return b.runeAt(n.right, pieceStart+n.p.length, offset)
+}
+
+// RuneAt returns the rune at offset.
+func (b *Buffer) RuneAt(offset int) rune {
+	return b.runeAt(b.root, 0, offset)
+}
+
+// growRightmost appends r to the add buffer and grows the rightmost
+// piece of the subtree rooted at n in place if that piece ends at the
+// add buffer's tail, so typing a run of runes doesn't grow the tree by
+// one node per keystroke. Returns false (leaving n and the add buffer
+// untouched) if it doesn't.
+func (b *Buffer) growRightmost(n *node, r rune) bool {
+	if n == nil {
+		return false
+	}
+	if n.right != nil {
+		if b.growRightmost(n.right, r) {
+			pull(n)
+			return true
+		}
+		return false
+	}
+	if !n.p.fromAdd || n.p.start+n.p.length != len(b.add) {
+		return false
+	}
+	b.add = append(b.add, r)
+	n.p.length++
+	if r == '\n' {
+		n.ownNL++
+		b.addNewlines = append(b.addNewlines, len(b.add)-1)
+	}
+	pull(n)
+	return true
+}
+
+// InsertRune inserts r at offset, which must be in [0, Len()].
+func (b *Buffer) InsertRune(offset int, r rune) {
+	left, right := b.split(b.root, offset)
+	if b.growRightmost(left, r) {
+		b.root = merge(left, right)
+		b.length++
+		return
+	}
+
+	b.add = append(b.add, r)
+	if r == '\n' {
+		b.addNewlines = append(b.addNewlines, len(b.add)-1)
+	}
+	newN := b.newNode(piece{fromAdd: true, start: len(b.add) - 1, length: 1})
+	b.root = merge(merge(left, newN), right)
+	b.length++
+}
+
+// DeleteRune removes the rune at offset, returning it. ok is false if
+// offset is out of range.
+func (b *Buffer) DeleteRune(offset int) (r rune, ok bool) {
+	if offset < 0 || offset >= b.length {
+		return 0, false
+	}
+	left, rest := b.split(b.root, offset)
+	mid, right := b.split(rest, 1)
+
+	src := b.original
+	if mid.p.fromAdd {
+		src = b.add
+	}
+	r = src[mid.p.start]
+
+	b.root = merge(left, right)
+	b.length--
+	return r, true
+}
@@ -0,0 +1,62 @@
+package buffer
+
+import (
+	"strings"
+	"testing"
+)
+
+// largeContent builds ~100MB of line-structured text, the size the
+// piece-table rework was meant to make editing cheap on.
+func largeContent(b *testing.B) []rune {
+	b.Helper()
+	const targetBytes = 100 * 1024 * 1024
+	const line = "the quick brown fox jumps over the lazy dog\n"
+	var sb strings.Builder
+	sb.Grow(targetBytes + len(line))
+	for sb.Len() < targetBytes {
+		sb.WriteString(line)
+	}
+	return []rune(sb.String())
+}
+
+// BenchmarkInsertRuneOn100MBFile measures the cost of a single
+// mid-document insert once the buffer already holds ~100MB, which is
+// what the piece table (O(log P) split/merge) is supposed to keep
+// cheap versus the old [][]rune's O(n) line copy.
+func BenchmarkInsertRuneOn100MBFile(b *testing.B) {
+	content := largeContent(b)
+	buf := New(content)
+	mid := buf.Len() / 2
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.InsertRune(mid, 'x')
+	}
+}
+
+// BenchmarkDeleteRuneOn100MBFile is InsertRune's counterpart for
+// deletes.
+func BenchmarkDeleteRuneOn100MBFile(b *testing.B) {
+	content := largeContent(b)
+	buf := New(content)
+	mid := buf.Len() / 2
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.DeleteRune(mid)
+		buf.InsertRune(mid, 'x') // keep the buffer size stable across iterations
+	}
+}
+
+// BenchmarkLineOn100MBFile measures seeking to a viewport line deep
+// into a large file, the operation render() calls on every frame.
+func BenchmarkLineOn100MBFile(b *testing.B) {
+	content := largeContent(b)
+	buf := New(content)
+	target := buf.LineCount() - 100
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Line(target)
+	}
+}
@@ -0,0 +1,188 @@
+package buffer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestNewAndString(t *testing.T) {
+	b := New([]rune("hello\nworld"))
+	if got := b.String(); got != "hello\nworld" {
+		t.Fatalf("String() = %q, want %q", got, "hello\nworld")
+	}
+	if b.Len() != 11 {
+		t.Fatalf("Len() = %d, want 11", b.Len())
+	}
+	if b.LineCount() != 2 {
+		t.Fatalf("LineCount() = %d, want 2", b.LineCount())
+	}
+}
+
+func TestInsertRuneAppendsAndSplits(t *testing.T) {
+	b := New([]rune("ac"))
+	b.InsertRune(1, 'b')
+	if got := b.String(); got != "abc" {
+		t.Fatalf("String() = %q, want %q", got, "abc")
+	}
+
+	b.InsertRune(0, 'X')
+	if got := b.String(); got != "Xabc" {
+		t.Fatalf("String() = %q, want %q", got, "Xabc")
+	}
+
+	b.InsertRune(b.Len(), 'Y')
+	if got := b.String(); got != "XabcY" {
+		t.Fatalf("String() = %q, want %q", got, "XabcY")
+	}
+}
+
+func TestInsertRuneGrowsRunInPlace(t *testing.T) {
+	b := New(nil)
+	for _, r := range "hello" {
+		b.InsertRune(b.Len(), r)
+	}
+	if got := b.String(); got != "hello" {
+		t.Fatalf("String() = %q, want %q", got, "hello")
+	}
+	if b.root == nil || b.root.left != nil || b.root.right != nil || b.root.p.length != 5 {
+		t.Fatal("typing a run built more than one piece, want a single piece grown in place")
+	}
+}
+
+func TestDeleteRune(t *testing.T) {
+	b := New([]rune("hello"))
+
+	r, ok := b.DeleteRune(0)
+	if !ok || r != 'h' {
+		t.Fatalf("DeleteRune(0) = (%q, %v), want ('h', true)", r, ok)
+	}
+	if got := b.String(); got != "ello" {
+		t.Fatalf("String() = %q, want %q", got, "ello")
+	}
+
+	r, ok = b.DeleteRune(b.Len() - 1)
+	if !ok || r != 'o' {
+		t.Fatalf("DeleteRune(last) = (%q, %v), want ('o', true)", r, ok)
+	}
+	if got := b.String(); got != "ell" {
+		t.Fatalf("String() = %q, want %q", got, "ell")
+	}
+
+	r, ok = b.DeleteRune(1)
+	if !ok || r != 'l' {
+		t.Fatalf("DeleteRune(1) = (%q, %v), want ('l', true)", r, ok)
+	}
+	if got := b.String(); got != "el" {
+		t.Fatalf("String() = %q, want %q", got, "el")
+	}
+
+	if _, ok := b.DeleteRune(-1); ok {
+		t.Fatal("DeleteRune(-1) = true, want false")
+	}
+	if _, ok := b.DeleteRune(b.Len()); ok {
+		t.Fatal("DeleteRune(Len()) = true, want false")
+	}
+}
+
+func TestLineStartLineCountAndCol(t *testing.T) {
+	b := New([]rune("one\ntwo\nthree"))
+	if b.LineCount() != 3 {
+		t.Fatalf("LineCount() = %d, want 3", b.LineCount())
+	}
+	wantStarts := []int{0, 4, 8}
+	for i, want := range wantStarts {
+		if got := b.LineStart(i); got != want {
+			t.Fatalf("LineStart(%d) = %d, want %d", i, got, want)
+		}
+	}
+	if got := b.LineStart(3); got != b.Len() {
+		t.Fatalf("LineStart(LineCount()) = %d, want %d", got, b.Len())
+	}
+
+	for offset, wantLine := range map[int]int{0: 0, 3: 0, 4: 1, 7: 1, 8: 2, 12: 2} {
+		line, _ := b.LineCol(offset)
+		if line != wantLine {
+			t.Fatalf("LineCol(%d) line = %d, want %d", offset, line, wantLine)
+		}
+	}
+}
+
+func TestLineMaterializesWithoutTrailingNewline(t *testing.T) {
+	b := New([]rune("one\ntwo\nthree"))
+	if got := string(b.Line(0)); got != "one" {
+		t.Fatalf("Line(0) = %q, want %q", got, "one")
+	}
+	if got := string(b.Line(1)); got != "two" {
+		t.Fatalf("Line(1) = %q, want %q", got, "two")
+	}
+	if got := string(b.Line(2)); got != "three" {
+		t.Fatalf("Line(2) = %q, want %q", got, "three")
+	}
+}
+
+func TestInsertAndDeleteNewlinesUpdateLineIndex(t *testing.T) {
+	b := New([]rune("ab"))
+	b.InsertRune(1, '\n')
+	if got := b.String(); got != "a\nb" {
+		t.Fatalf("String() = %q, want %q", got, "a\nb")
+	}
+	if b.LineCount() != 2 {
+		t.Fatalf("LineCount() = %d, want 2", b.LineCount())
+	}
+	if got := string(b.Line(1)); got != "b" {
+		t.Fatalf("Line(1) = %q, want %q", got, "b")
+	}
+
+	b.DeleteRune(1)
+	if got := b.String(); got != "ab" {
+		t.Fatalf("String() = %q, want %q", got, "ab")
+	}
+	if b.LineCount() != 1 {
+		t.Fatalf("LineCount() = %d, want 1", b.LineCount())
+	}
+}
+
+// TestRandomEditsMatchReferenceModel drives Buffer and a naive []rune
+// reference through the same random sequence of inserts and deletes,
+// checking the buffer's content and line index against the reference
+// after every step. This exercises the treap split/merge boundary
+// cases (split at the very start/end of a piece, inside a piece, and
+// across piece and subtree boundaries) far more thoroughly than
+// hand-picked cases can.
+func TestRandomEditsMatchReferenceModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []rune("ab\nc\nd")
+
+	var ref []rune
+	b := New(nil)
+
+	for i := 0; i < 5000; i++ {
+		if len(ref) == 0 || rng.Intn(2) == 0 {
+			offset := rng.Intn(len(ref) + 1)
+			r := alphabet[rng.Intn(len(alphabet))]
+			b.InsertRune(offset, r)
+			ref = append(ref, 0)
+			copy(ref[offset+1:], ref[offset:])
+			ref[offset] = r
+		} else {
+			offset := rng.Intn(len(ref))
+			want := ref[offset]
+			got, ok := b.DeleteRune(offset)
+			if !ok {
+				t.Fatalf("step %d: DeleteRune(%d) = false, want true", i, offset)
+			}
+			if got != want {
+				t.Fatalf("step %d: DeleteRune(%d) = %q, want %q", i, offset, got, want)
+			}
+			ref = append(ref[:offset], ref[offset+1:]...)
+		}
+
+		if got := b.String(); got != string(ref) {
+			t.Fatalf("step %d: String() = %q, want %q", i, got, string(ref))
+		}
+		if wantLines := strings.Count(string(ref), "\n") + 1; b.LineCount() != wantLines {
+			t.Fatalf("step %d: LineCount() = %d, want %d", i, b.LineCount(), wantLines)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package buffer
+
+import (
+	"os"
+	"testing"
+)
+
+func TestOpenReadsFileContent(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "dm-buffer-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "line one\nline two\nline three\n"
+	if _, err := f.WriteString(want); err != nil {
+		t.Fatal(err)
+	}
+	path := f.Name()
+	f.Close()
+
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if got := b.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+	if b.LineCount() != 4 {
+		t.Fatalf("LineCount() = %d, want 4", b.LineCount())
+	}
+}
+
+func TestOpenEmptyFile(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "dm-buffer-empty-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := f.Name()
+	f.Close()
+
+	b, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if got := b.String(); got != "" {
+		t.Fatalf("String() = %q, want empty", got)
+	}
+}
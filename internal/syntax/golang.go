@@ -0,0 +1,92 @@
+package syntax
+
+// goInBlockComment is the LexerState carried between lines while
+// inside an unterminated /* */ comment.
+const goInBlockComment LexerState = 1
+
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+	"true": true, "false": true, "nil": true, "iota": true,
+}
+
+type goLexer struct{}
+
+func (goLexer) Tokenize(line []rune, state LexerState) ([]Token, LexerState) {
+	n := len(line)
+	i := 0
+	var tokens []Token
+
+	if state == goInBlockComment {
+		start := i
+		for i < n && !(i+1 < n && line[i] == '*' && line[i+1] == '/') {
+			i++
+		}
+		if i+1 >= n {
+			return []Token{{Kind: Comment, Start: start, End: n}}, goInBlockComment
+		}
+		i += 2
+		tokens = append(tokens, Token{Kind: Comment, Start: start, End: i})
+	}
+
+	for i < n {
+		c := line[i]
+		switch {
+		case c == '/' && i+1 < n && line[i+1] == '/':
+			tokens = append(tokens, Token{Kind: Comment, Start: i, End: n})
+			i = n
+
+		case c == '/' && i+1 < n && line[i+1] == '*':
+			start := i
+			i += 2
+			for i < n && !(i+1 < n && line[i] == '*' && line[i+1] == '/') {
+				i++
+			}
+			if i+1 >= n {
+				tokens = append(tokens, Token{Kind: Comment, Start: start, End: n})
+				return tokens, goInBlockComment
+			}
+			i += 2
+			tokens = append(tokens, Token{Kind: Comment, Start: start, End: i})
+
+		case c == '"' || c == '`':
+			quote := c
+			start := i
+			i++
+			for i < n && line[i] != quote {
+				if quote == '"' && line[i] == '\\' && i+1 < n {
+					i++
+				}
+				i++
+			}
+			if i < n {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: String, Start: start, End: i})
+
+		case isDigit(c):
+			start := i
+			for i < n && (isHexDigit(line[i]) || line[i] == '.' || line[i] == 'x' || line[i] == 'X') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: Number, Start: start, End: i})
+
+		case isIdentStart(c):
+			start := i
+			for i < n && isIdentPart(line[i]) {
+				i++
+			}
+			if goKeywords[string(line[start:i])] {
+				tokens = append(tokens, Token{Kind: Keyword, Start: start, End: i})
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return tokens, 0
+}
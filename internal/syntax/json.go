@@ -0,0 +1,53 @@
+package syntax
+
+var jsonKeywords = map[string]bool{"true": true, "false": true, "null": true}
+
+// jsonLexer has no multi-line constructs, so state is always 0.
+type jsonLexer struct{}
+
+func (jsonLexer) Tokenize(line []rune, _ LexerState) ([]Token, LexerState) {
+	n := len(line)
+	i := 0
+	var tokens []Token
+
+	for i < n {
+		c := line[i]
+		switch {
+		case c == '"':
+			start := i
+			i++
+			for i < n && line[i] != '"' {
+				if line[i] == '\\' && i+1 < n {
+					i++
+				}
+				i++
+			}
+			if i < n {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: String, Start: start, End: i})
+
+		case c == '-' || isDigit(c):
+			start := i
+			i++
+			for i < n && (isDigit(line[i]) || line[i] == '.' || line[i] == 'e' || line[i] == 'E' || line[i] == '+' || line[i] == '-') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: Number, Start: start, End: i})
+
+		case isIdentStart(c):
+			start := i
+			for i < n && isIdentPart(line[i]) {
+				i++
+			}
+			if jsonKeywords[string(line[start:i])] {
+				tokens = append(tokens, Token{Kind: Keyword, Start: start, End: i})
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return tokens, 0
+}
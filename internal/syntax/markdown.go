@@ -0,0 +1,62 @@
+package syntax
+
+// mdInFencedCode is the LexerState carried between lines while inside
+// a ``` fenced code block.
+const mdInFencedCode LexerState = 1
+
+type markdownLexer struct{}
+
+func (markdownLexer) Tokenize(line []rune, state LexerState) ([]Token, LexerState) {
+	n := len(line)
+	fence := isFenceLine(line)
+
+	if state == mdInFencedCode {
+		if fence {
+			return []Token{{Kind: String, Start: 0, End: n}}, 0
+		}
+		return []Token{{Kind: String, Start: 0, End: n}}, mdInFencedCode
+	}
+	if fence {
+		return []Token{{Kind: String, Start: 0, End: n}}, mdInFencedCode
+	}
+
+	if n > 0 && line[0] == '#' {
+		i := 0
+		for i < n && line[i] == '#' {
+			i++
+		}
+		if i < n && line[i] == ' ' {
+			return []Token{{Kind: Keyword, Start: 0, End: n}}, 0
+		}
+	}
+	if n > 0 && line[0] == '>' {
+		return []Token{{Kind: Comment, Start: 0, End: n}}, 0
+	}
+
+	var tokens []Token
+	for i := 0; i < n; {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		start := i
+		i++
+		for i < n && line[i] != '`' {
+			i++
+		}
+		if i < n {
+			i++
+		}
+		tokens = append(tokens, Token{Kind: String, Start: start, End: i})
+	}
+	return tokens, 0
+}
+
+func isFenceLine(line []rune) bool {
+	n := len(line)
+	i := 0
+	for i < n && line[i] == ' ' {
+		i++
+	}
+	return i+3 <= n && line[i] == '`' && line[i+1] == '`' && line[i+2] == '`'
+}
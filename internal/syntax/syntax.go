@@ -0,0 +1,145 @@
+// Package syntax tokenizes source lines for render() to colorize.
+// Lexers are line-at-a-time so render() only has to re-tokenize the
+// handful of lines actually on screen; LexerState carries whatever
+// multi-line context a lexer needs (e.g. "inside a block comment")
+// from one line to the next.
+package syntax
+
+import (
+	"hash/fnv"
+	"path/filepath"
+	"unicode"
+)
+
+// TokenKind classifies a span of a line for highlighting.
+type TokenKind int
+
+const (
+	Plain TokenKind = iota
+	Keyword
+	String
+	Comment
+	Number
+)
+
+// Token is a highlighted span [Start, End) of rune indices into the
+// line it came from.
+type Token struct {
+	Kind  TokenKind
+	Start int
+	End   int
+}
+
+// LexerState carries multi-line lexing context (e.g. "inside a /* */
+// comment") from one line to the next. Its zero value is always a
+// valid starting state.
+type LexerState int
+
+// Lexer tokenizes a single line, given the state left over from the
+// previous line, and returns the state to carry into the next one.
+type Lexer interface {
+	Tokenize(line []rune, state LexerState) ([]Token, LexerState)
+}
+
+// ForExt returns the built-in lexer for a filename extension (as
+// returned by filepath.Ext, dot included), or a lexer that performs no
+// highlighting if the extension isn't recognized.
+func ForExt(ext string) Lexer {
+	switch ext {
+	case ".go":
+		return goLexer{}
+	case ".md", ".markdown":
+		return markdownLexer{}
+	case ".json":
+		return jsonLexer{}
+	default:
+		return plainLexer{}
+	}
+}
+
+// ForFile is a convenience wrapper around ForExt for a full filename.
+func ForFile(filename string) Lexer {
+	return ForExt(filepath.Ext(filename))
+}
+
+type plainLexer struct{}
+
+func (plainLexer) Tokenize(line []rune, state LexerState) ([]Token, LexerState) {
+	return nil, state
+}
+
+// cacheKey identifies a line by content hash plus incoming state, so
+// the same line re-lexes to a cache hit regardless of where it sits in
+// the document.
+type cacheKey struct {
+	hash  uint64
+	state LexerState
+}
+
+type cacheEntry struct {
+	tokens   []Token
+	outState LexerState
+}
+
+// maxCacheEntries bounds Cache the same way main.go bounds undo history
+// (see maxUndoOps): the key includes the edited line's content hash, so
+// every edit to the line under the cursor mints a new entry and the old
+// one is never revisited, making growth unbounded by document size
+// without a cap.
+const maxCacheEntries = 4096
+
+// Cache memoizes a Lexer's output per (line content, incoming state)
+// so unchanged lines aren't re-lexed on every render, e.g. when the
+// cursor moves without editing. It's capped at maxCacheEntries,
+// evicting the oldest entry once full.
+type Cache struct {
+	lexer Lexer
+	cache map[cacheKey]cacheEntry
+	order []cacheKey // insertion order, oldest first, for eviction
+}
+
+// NewCache wraps lexer with a tokenization cache.
+func NewCache(lexer Lexer) *Cache {
+	return &Cache{lexer: lexer, cache: make(map[cacheKey]cacheEntry)}
+}
+
+// Tokenize returns lexer's tokens for line, using the cache when the
+// (line, state) pair has been seen before.
+func (c *Cache) Tokenize(line []rune, state LexerState) ([]Token, LexerState) {
+	key := cacheKey{hash: hashLine(line), state: state}
+	if entry, ok := c.cache[key]; ok {
+		return entry.tokens, entry.outState
+	}
+	tokens, outState := c.lexer.Tokenize(line, state)
+	c.cache[key] = cacheEntry{tokens: tokens, outState: outState}
+	c.order = append(c.order, key)
+	if len(c.order) > maxCacheEntries {
+		delete(c.cache, c.order[0])
+		c.order = c.order[1:]
+	}
+	return tokens, outState
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+func hashLine(line []rune) uint64 {
+	h := fnv.New64a()
+	for _, r := range line {
+		h.Write([]byte{byte(r), byte(r >> 8), byte(r >> 16)})
+	}
+	return h.Sum64()
+}
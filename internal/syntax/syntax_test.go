@@ -0,0 +1,32 @@
+package syntax
+
+import "testing"
+
+func TestCacheReusesEntryForRepeatedLine(t *testing.T) {
+	c := NewCache(plainLexer{})
+	line := []rune("package main")
+
+	c.Tokenize(line, 0)
+	if len(c.cache) != 1 {
+		t.Fatalf("len(cache) = %d, want 1", len(c.cache))
+	}
+	c.Tokenize(line, 0)
+	if len(c.cache) != 1 {
+		t.Fatalf("len(cache) after repeat = %d, want 1", len(c.cache))
+	}
+}
+
+func TestCacheEvictsOldestEntryOverCap(t *testing.T) {
+	c := NewCache(plainLexer{})
+
+	for i := 0; i < maxCacheEntries+10; i++ {
+		c.Tokenize([]rune{rune('a' + i%26), rune(i)}, 0)
+	}
+
+	if len(c.cache) != maxCacheEntries {
+		t.Fatalf("len(cache) = %d, want %d", len(c.cache), maxCacheEntries)
+	}
+	if len(c.order) != maxCacheEntries {
+		t.Fatalf("len(order) = %d, want %d", len(c.order), maxCacheEntries)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package term
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize subscribes ch to SIGWINCH, the signal POSIX terminals
+// send on a window size change.
+func notifyResize(ch chan os.Signal) {
+	signal.Notify(ch, syscall.SIGWINCH)
+}
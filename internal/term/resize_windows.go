@@ -0,0 +1,9 @@
+//go:build windows
+
+package term
+
+import "os"
+
+// notifyResize is a no-op on Windows, which has no SIGWINCH; callers
+// fall back to whatever resize detection the platform offers.
+func notifyResize(ch chan os.Signal) {}
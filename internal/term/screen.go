@@ -0,0 +1,167 @@
+package term
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// cell is a single character plus the SGR escape sequence that should
+// precede it, if any ("" means the terminal's default style).
+type cell struct {
+	ch    rune
+	style string
+}
+
+// Screen is a back buffer that diffs against the previously flushed
+// frame so Flush only emits escape sequences for cells that actually
+// changed, instead of clearing and redrawing the whole terminal.
+//
+// mu guards every field below: Resize is called from the terminal's
+// SIGWINCH handler goroutine while the main loop may be mid-render, and
+// without a lock a resize landing between a Set* call and Flush reads
+// cur/prev against stale width/height and indexes out of range.
+type Screen struct {
+	mu            sync.Mutex
+	width, height int
+	cur, prev     []cell
+	cursorX       int
+	cursorY       int
+}
+
+// NewScreen creates a Screen sized to width x height cells.
+func NewScreen(width, height int) *Screen {
+	s := &Screen{}
+	s.Resize(width, height)
+	return s
+}
+
+// Resize changes the screen dimensions, discarding the previous frame
+// so the next Flush does a full redraw.
+func (s *Screen) Resize(width, height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.width, s.height = width, height
+	s.cur = make([]cell, width*height)
+	s.prev = nil
+	s.clear(s.cur)
+}
+
+func (s *Screen) clear(cells []cell) {
+	for i := range cells {
+		cells[i] = cell{ch: ' '}
+	}
+}
+
+// Size returns the screen's current dimensions in cells.
+func (s *Screen) Size() (width, height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.width, s.height
+}
+
+// Set writes a single plain rune at (x, y) into the back buffer.
+// Writes outside the screen bounds are ignored.
+func (s *Screen) Set(x, y int, r rune) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setStyled(x, y, r, "")
+}
+
+// SetStyled writes a single rune at (x, y) preceded by an SGR escape
+// sequence (e.g. "\033[34m") when rendered. An empty style means the
+// terminal's default style: Flush resets attributes before such a cell
+// if a styled cell preceded it.
+func (s *Screen) SetStyled(x, y int, r rune, style string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setStyled(x, y, r, style)
+}
+
+// setStyled is SetStyled without locking, for callers that already
+// hold mu.
+func (s *Screen) setStyled(x, y int, r rune, style string) {
+	if x < 0 || x >= s.width || y < 0 || y >= s.height {
+		return
+	}
+	s.cur[y*s.width+x] = cell{ch: r, style: style}
+}
+
+// SetString writes a plain string starting at (x, y), truncating at
+// the right edge of the screen.
+func (s *Screen) SetString(x, y int, str string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range str {
+		s.setStyled(x, y, r, "")
+		x++
+	}
+}
+
+// SetStringStyled writes str starting at (x, y), with style applied to
+// every rune in it.
+func (s *Screen) SetStringStyled(x, y int, str string, style string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range str {
+		s.setStyled(x, y, r, style)
+		x++
+	}
+}
+
+// SetCursor records where the terminal cursor should land after Flush.
+func (s *Screen) SetCursor(x, y int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cursorX, s.cursorY = x, y
+}
+
+// Flush writes only the cells that changed since the last Flush, then
+// positions the cursor and starts a fresh back buffer for the next
+// frame. On the first call (or after Resize) every cell is considered
+// changed.
+func (s *Screen) Flush(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out strings.Builder
+	if s.prev == nil {
+		out.WriteString("\033[2J")
+	}
+
+	lastRow, lastCol := -1, -1
+	lastStyle := ""
+	for y := 0; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			i := y*s.width + x
+			if s.prev != nil && s.cur[i] == s.prev[i] {
+				continue
+			}
+			if y != lastRow || x != lastCol {
+				fmt.Fprintf(&out, "\033[%d;%dH", y+1, x+1)
+			}
+			if s.cur[i].style != lastStyle {
+				out.WriteString("\033[0m")
+				out.WriteString(s.cur[i].style)
+				lastStyle = s.cur[i].style
+			}
+			out.WriteRune(s.cur[i].ch)
+			lastRow, lastCol = y, x+1
+		}
+	}
+	if lastStyle != "" {
+		out.WriteString("\033[0m")
+	}
+
+	fmt.Fprintf(&out, "\033[%d;%dH", s.cursorY+1, s.cursorX+1)
+
+	if _, err := io.WriteString(w, out.String()); err != nil {
+		return err
+	}
+
+	s.prev = s.cur
+	s.cur = make([]cell, s.width*s.height)
+	s.clear(s.cur)
+	return nil
+}
@@ -0,0 +1,38 @@
+package term
+
+import (
+	"io"
+	"sync"
+	"testing"
+)
+
+// TestScreenConcurrentResizeAndRender hammers Resize (as the SIGWINCH
+// handler goroutine does) against SetString/Flush (as the main render
+// loop does) to catch the data race between them under -race.
+func TestScreenConcurrentResizeAndRender(t *testing.T) {
+	s := NewScreen(80, 24)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			w, h := 40+i%40, 20+i%10
+			s.Resize(w, h)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			s.SetString(0, 0, "hello, dm")
+			s.SetCursor(i%10, i%5)
+			if err := s.Flush(io.Discard); err != nil {
+				t.Errorf("Flush: %v", err)
+			}
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,88 @@
+// Package term wraps golang.org/x/term to give dm raw-mode input and
+// reliable size queries without shelling out to stty, and adds a
+// diffing screen buffer so redraws only touch changed cells.
+package term
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// Terminal holds the raw-mode state for a single fd so it can be
+// restored on exit.
+type Terminal struct {
+	fd       int
+	oldState *term.State
+}
+
+// Open puts fd into raw mode and returns a Terminal that can restore
+// it. Callers should defer Restore.
+func Open(fd int) (*Terminal, error) {
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	return &Terminal{fd: fd, oldState: oldState}, nil
+}
+
+// Restore puts the terminal back into whatever mode it was in before
+// Open. Safe to call more than once.
+func (t *Terminal) Restore() error {
+	if t.oldState == nil {
+		return nil
+	}
+	err := term.Restore(t.fd, t.oldState)
+	t.oldState = nil
+	return err
+}
+
+// Size returns the current terminal width and height in character
+// cells.
+func (t *Terminal) Size() (width, height int, err error) {
+	return term.GetSize(t.fd)
+}
+
+// HandleSignals calls onResize with the new size on a window resize
+// (SIGWINCH on platforms that have it), and closes the returned quit
+// channel on SIGINT/SIGTERM instead of tearing anything down itself:
+// restoring the terminal and releasing other resources is the caller's
+// job (its own defers), so a signal has to unwind through the same
+// path a normal quit does rather than calling os.Exit from in here. It
+// also returns a stop func that unregisters the handlers; callers
+// should defer it alongside Restore.
+func (t *Terminal) HandleSignals(onResize func(width, height int)) (quit <-chan struct{}, stop func()) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	resizeCh := make(chan os.Signal, 1)
+	notifyResize(resizeCh)
+
+	quitCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		signaled := sigCh
+		for {
+			select {
+			case <-signaled:
+				close(quitCh)
+				signaled = nil // already signaled; stop selecting it so we don't double-close
+			case <-resizeCh:
+				if onResize == nil {
+					continue
+				}
+				if w, h, err := t.Size(); err == nil {
+					onResize(w, h)
+				}
+			case <-done:
+				signal.Stop(sigCh)
+				signal.Stop(resizeCh)
+				return
+			}
+		}
+	}()
+
+	return quitCh, func() { close(done) }
+}
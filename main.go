@@ -1,151 +1,666 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/eiannone/keyboard"
+
+	"github.com/DHMorse/dm/internal/buffer"
+	"github.com/DHMorse/dm/internal/syntax"
+	"github.com/DHMorse/dm/internal/term"
 )
 
+const historyFileName = ".dm_history"
+
+// errPromptCancelled is returned by Prompt when the user aborts with
+// Esc or Ctrl-C.
+var errPromptCancelled = errors.New("prompt cancelled")
+
+const (
+	maxUndoOps   = 1000
+	groupTimeout = time.Second
+)
+
+// op is a single reversible edit: a rune (which may be '\n') inserted
+// at or removed from offset. insert records which direction the op ran
+// so Undo/Redo know whether to replay it or its inverse.
+type op struct {
+	offset int
+	r      rune
+	insert bool
+}
+
 type Editor struct {
-	content  [][]rune
+	buf      *buffer.Buffer
 	cursorX  int
 	cursorY  int
 	filename string
 	modified bool
+
+	undoStack     [][]op
+	redoStack     [][]op
+	group         []op
+	groupDeadline time.Time
+	replaying     bool
+
+	term   *term.Terminal
+	screen *term.Screen
+
+	history     []string
+	historyPath string
+
+	lexCache *syntax.Cache
+	// lexCheckpoints[i] is the LexerState entering line i*lexCheckpointStride.
+	// render() replays from the nearest checkpoint below the viewport
+	// instead of from line 0, so scrolling deep into a large file costs
+	// O(stride) per frame instead of O(viewport line number).
+	lexCheckpoints []syntax.LexerState
+}
+
+// lexCheckpointStride is how many lines apart cached lexer-state
+// checkpoints are kept.
+const lexCheckpointStride = 256
+
+// lexStateBefore returns the LexerState in effect just before line
+// startLine, building and caching any checkpoints it needs along the
+// way.
+func (e *Editor) lexStateBefore(startLine, lineCount int) syntax.LexerState {
+	if len(e.lexCheckpoints) == 0 {
+		e.lexCheckpoints = []syntax.LexerState{0}
+	}
+
+	idx := startLine / lexCheckpointStride
+	for len(e.lexCheckpoints) <= idx {
+		last := len(e.lexCheckpoints) - 1
+		state := e.lexCheckpoints[last]
+		from := last * lexCheckpointStride
+		to := from + lexCheckpointStride
+		if to > lineCount {
+			to = lineCount
+		}
+		for l := from; l < to; l++ {
+			_, state = e.lexCache.Tokenize(e.buf.Line(l), state)
+		}
+		e.lexCheckpoints = append(e.lexCheckpoints, state)
+	}
+
+	state := e.lexCheckpoints[idx]
+	from := idx * lexCheckpointStride
+	to := startLine
+	if to > lineCount {
+		to = lineCount
+	}
+	for l := from; l < to; l++ {
+		_, state = e.lexCache.Tokenize(e.buf.Line(l), state)
+	}
+	return state
+}
+
+// invalidateLexCheckpoints drops cached checkpoints that could be
+// stale after an edit starting at line. A checkpoint at i*stride <=
+// line is still valid: it only reflects lines strictly before the
+// edit.
+func (e *Editor) invalidateLexCheckpoints(line int) {
+	keep := line/lexCheckpointStride + 1
+	if keep < len(e.lexCheckpoints) {
+		e.lexCheckpoints = e.lexCheckpoints[:keep]
+	}
+}
+
+// attachTerminal gives the editor a raw-mode terminal to size and
+// render against, creating the initial back buffer.
+func (e *Editor) attachTerminal(t *term.Terminal) error {
+	e.term = t
+	width, height, err := t.Size()
+	if err != nil {
+		return err
+	}
+	e.screen = term.NewScreen(width, height)
+	return nil
+}
+
+// handleResize is called from the terminal's SIGWINCH handler.
+func (e *Editor) handleResize(width, height int) {
+	if e.screen != nil {
+		e.screen.Resize(width, height)
+	}
 }
 
 func NewEditor(filename string) (*Editor, error) {
 	editor := &Editor{
-		content:  make([][]rune, 1),
+		buf:      buffer.New(nil),
 		filename: filename,
 	}
-	editor.content[0] = make([]rune, 0)
+	editor.historyPath = historyFilePath()
+	editor.history = loadHistory(editor.historyPath)
+	editor.lexCache = syntax.NewCache(syntax.ForFile(filename))
 
 	// Try to read existing file
 	if _, err := os.Stat(filename); err == nil {
-		content, err := os.ReadFile(filename)
+		buf, err := buffer.Open(filename)
 		if err != nil {
 			return nil, err
 		}
-		lines := strings.Split(string(content), "\n")
-		editor.content = make([][]rune, len(lines))
-		for i, line := range lines {
-			editor.content[i] = []rune(line)
-		}
+		editor.buf = buf
 	}
 
 	return editor, nil
 }
 
-func (e *Editor) insertRune(r rune) {
-	line := e.content[e.cursorY]
-	if e.cursorX == len(line) {
-		e.content[e.cursorY] = append(line, r)
-	} else {
-		newLine := make([]rune, len(line)+1)
-		copy(newLine, line[:e.cursorX])
-		newLine[e.cursorX] = r
-		copy(newLine[e.cursorX+1:], line[e.cursorX:])
-		e.content[e.cursorY] = newLine
+// historyFilePath returns the path dm persists prompt history to,
+// or "" if the user's home directory can't be determined.
+func historyFilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFileName)
+}
+
+// loadHistory reads previously saved prompt entries, oldest first. A
+// missing or unreadable file just means no history yet.
+func loadHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+	return history
+}
+
+// appendHistory records entry in memory and, best-effort, on disk so it
+// survives to the next session.
+func (e *Editor) appendHistory(entry string) {
+	e.history = append(e.history, entry)
+	if e.historyPath == "" {
+		return
+	}
+	f, err := os.OpenFile(e.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, entry)
+}
+
+// Prompt reads a line on the bottom of the screen with readline-style
+// editing: left/right/Home/End motion, Ctrl-W word delete, Ctrl-K
+// kill-to-end, Up/Down history, Ctrl-R reverse search, and Tab
+// completion via completer (which may be nil). It returns
+// errPromptCancelled if the user aborts with Esc or Ctrl-C.
+func (e *Editor) Prompt(msg string, completer func(string) []string) (string, error) {
+	buf := []rune{}
+	pos := 0
+	histIdx := len(e.history)
+	var saved string
+
+	redraw := func() {
+		if e.screen != nil {
+			_, height := e.screen.Size()
+			fmt.Printf("\033[%d;1H", height)
+		}
+		fmt.Print("\033[2K\r")
+		fmt.Print(msg)
+		fmt.Print(string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Printf("\033[%dD", back)
+		}
+	}
+	redraw()
+
+	for {
+		char, key, err := keyboard.GetKey()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyboard.KeyEnter:
+			fmt.Println()
+			result := string(buf)
+			if result != "" {
+				e.appendHistory(result)
+			}
+			return result, nil
+		case keyboard.KeyEsc, keyboard.KeyCtrlC:
+			fmt.Println()
+			return "", errPromptCancelled
+		case keyboard.KeyArrowLeft:
+			if pos > 0 {
+				pos--
+			}
+		case keyboard.KeyArrowRight:
+			if pos < len(buf) {
+				pos++
+			}
+		case keyboard.KeyHome:
+			pos = 0
+		case keyboard.KeyEnd:
+			pos = len(buf)
+		case keyboard.KeyCtrlW:
+			start := pos
+			for start > 0 && buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && buf[start-1] != ' ' {
+				start--
+			}
+			buf = append(buf[:start], buf[pos:]...)
+			pos = start
+		case keyboard.KeyCtrlK:
+			buf = buf[:pos]
+		case keyboard.KeyArrowUp:
+			if histIdx > 0 {
+				if histIdx == len(e.history) {
+					saved = string(buf)
+				}
+				histIdx--
+				buf = []rune(e.history[histIdx])
+				pos = len(buf)
+			}
+		case keyboard.KeyArrowDown:
+			if histIdx < len(e.history) {
+				histIdx++
+				if histIdx == len(e.history) {
+					buf = []rune(saved)
+				} else {
+					buf = []rune(e.history[histIdx])
+				}
+				pos = len(buf)
+			}
+		case keyboard.KeyCtrlR:
+			if match, ok := e.reverseSearch(); ok {
+				buf = []rune(match)
+				pos = len(buf)
+			}
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+			}
+		case keyboard.KeyTab:
+			if completer != nil {
+				if matches := completer(string(buf)); len(matches) == 1 {
+					buf = []rune(matches[0])
+					pos = len(buf)
+				}
+			}
+		default:
+			if char != 0 {
+				buf = append(buf[:pos], append([]rune{char}, buf[pos:]...)...)
+				pos++
+			}
+		}
+		redraw()
+	}
+}
+
+// reverseSearch implements Ctrl-R incremental history search: typing
+// narrows the match, repeated Ctrl-R walks to older matches.
+func (e *Editor) reverseSearch() (string, bool) {
+	query := []rune{}
+	match := ""
+	idx := len(e.history) - 1
+
+	find := func() {
+		for i := idx; i >= 0; i-- {
+			if strings.Contains(e.history[i], string(query)) {
+				match = e.history[i]
+				idx = i
+				return
+			}
+		}
+		match = ""
+	}
+	redraw := func() {
+		fmt.Print("\033[2K\r")
+		fmt.Printf("(reverse-i-search)`%s': %s", string(query), match)
+	}
+	redraw()
+
+	for {
+		char, key, err := keyboard.GetKey()
+		if err != nil {
+			return "", false
+		}
+
+		switch key {
+		case keyboard.KeyEnter:
+			fmt.Println()
+			return match, match != ""
+		case keyboard.KeyEsc, keyboard.KeyCtrlC:
+			fmt.Println()
+			return "", false
+		case keyboard.KeyCtrlR:
+			idx--
+			find()
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				idx = len(e.history) - 1
+				find()
+			}
+		default:
+			if char != 0 {
+				query = append(query, char)
+				idx = len(e.history) - 1
+				find()
+			}
+		}
+		redraw()
 	}
+}
+
+// offset returns the buffer offset the cursor currently points at.
+func (e *Editor) offset() int {
+	return e.buf.LineStart(e.cursorY) + e.cursorX
+}
+
+// setCursorFromOffset moves the cursor to the line/column that offset
+// maps to.
+func (e *Editor) setCursorFromOffset(offset int) {
+	e.cursorY, e.cursorX = e.buf.LineCol(offset)
+}
+
+func (e *Editor) insertRune(r rune) {
+	off := e.offset()
+	e.invalidateLexCheckpoints(e.cursorY)
+	e.buf.InsertRune(off, r)
+	e.record(op{offset: off, r: r, insert: true})
 	e.cursorX++
 	e.modified = true
 }
 
 func (e *Editor) insertNewline() {
-	currentLine := e.content[e.cursorY]
-	rightPart := make([]rune, len(currentLine[e.cursorX:]))
-	copy(rightPart, currentLine[e.cursorX:])
-	e.content[e.cursorY] = currentLine[:e.cursorX]
-
-	// Insert new line
-	e.content = append(e.content[:e.cursorY+1], e.content[e.cursorY:]...)
-	e.content[e.cursorY+1] = rightPart
-
+	off := e.offset()
+	e.invalidateLexCheckpoints(e.cursorY)
+	e.buf.InsertRune(off, '\n')
+	e.record(op{offset: off, r: '\n', insert: true})
 	e.cursorY++
 	e.cursorX = 0
 	e.modified = true
 }
 
 func (e *Editor) backspace() {
-	if e.cursorX > 0 {
-		line := e.content[e.cursorY]
-		e.content[e.cursorY] = append(line[:e.cursorX-1], line[e.cursorX:]...)
-		e.cursorX--
-		e.modified = true
-	} else if e.cursorY > 0 {
-		// Merge with previous line
-		prevLine := e.content[e.cursorY-1]
-		currentLine := e.content[e.cursorY]
-		e.cursorX = len(prevLine)
-		e.content[e.cursorY-1] = append(prevLine, currentLine...)
-		e.content = append(e.content[:e.cursorY], e.content[e.cursorY+1:]...)
-		e.cursorY--
-		e.modified = true
+	if e.cursorX == 0 && e.cursorY == 0 {
+		return
+	}
+	off := e.offset() - 1
+	r, ok := e.buf.DeleteRune(off)
+	if !ok {
+		return
 	}
+	e.record(op{offset: off, r: r, insert: false})
+	e.setCursorFromOffset(off)
+	e.invalidateLexCheckpoints(e.cursorY)
+	e.modified = true
 }
 
-func (e *Editor) save() error {
-	var content strings.Builder
-	for i, line := range e.content {
-		content.WriteString(string(line))
-		if i < len(e.content)-1 {
-			content.WriteRune('\n')
-		}
+// record appends op to the in-progress undo group, flushing it first if
+// op can't be folded into it. Consecutive printable-rune insertions are
+// grouped into a single undoable transaction; a newline, a pause longer
+// than groupTimeout, or any other op starts a new group.
+func (e *Editor) record(o op) {
+	if e.replaying {
+		return
+	}
+	now := time.Now()
+	canFold := len(e.group) > 0 && o.insert && o.r != '\n' && now.Before(e.groupDeadline)
+	if !canFold {
+		e.flushGroup()
+	}
+	e.group = append(e.group, o)
+	e.groupDeadline = now.Add(groupTimeout)
+	e.redoStack = nil
+}
+
+// flushGroup closes out the in-progress undo group so the next edit (or
+// an explicit Undo) starts fresh. Call it on cursor movement, save, and
+// quit in addition to the idle timeout.
+func (e *Editor) flushGroup() {
+	if len(e.group) == 0 {
+		return
+	}
+	e.undoStack = append(e.undoStack, e.group)
+	if len(e.undoStack) > maxUndoOps {
+		e.undoStack = e.undoStack[1:]
+	}
+	e.group = nil
+}
+
+// Undo reverses the most recent undo group, if any, and returns true if
+// it did so. It replays each op's inverse in reverse order so it works
+// without a TTY, which is what makes it testable.
+func (e *Editor) Undo() bool {
+	e.flushGroup()
+	if len(e.undoStack) == 0 {
+		return false
 	}
-	err := os.WriteFile(e.filename, []byte(content.String()), 0644)
+	group := e.undoStack[len(e.undoStack)-1]
+	e.undoStack = e.undoStack[:len(e.undoStack)-1]
+
+	e.replaying = true
+	for i := len(group) - 1; i >= 0; i-- {
+		e.applyInverse(group[i])
+	}
+	e.replaying = false
+
+	e.redoStack = append(e.redoStack, group)
+	e.modified = true
+	return true
+}
+
+// Redo reapplies the most recently undone group, if any, and returns
+// true if it did so.
+func (e *Editor) Redo() bool {
+	if len(e.redoStack) == 0 {
+		return false
+	}
+	group := e.redoStack[len(e.redoStack)-1]
+	e.redoStack = e.redoStack[:len(e.redoStack)-1]
+
+	e.replaying = true
+	for _, o := range group {
+		e.applyForward(o)
+	}
+	e.replaying = false
+
+	e.undoStack = append(e.undoStack, group)
+	e.modified = true
+	return true
+}
+
+// applyForward replays o exactly as it originally happened.
+func (e *Editor) applyForward(o op) {
+	line, _ := e.buf.LineCol(o.offset)
+	e.invalidateLexCheckpoints(line)
+	if o.insert {
+		e.buf.InsertRune(o.offset, o.r)
+		e.setCursorFromOffset(o.offset + 1)
+	} else {
+		e.buf.DeleteRune(o.offset)
+		e.setCursorFromOffset(o.offset)
+	}
+}
+
+// applyInverse undoes o by performing the opposite mutation.
+func (e *Editor) applyInverse(o op) {
+	line, _ := e.buf.LineCol(o.offset)
+	e.invalidateLexCheckpoints(line)
+	if o.insert {
+		e.buf.DeleteRune(o.offset)
+		e.setCursorFromOffset(o.offset)
+	} else {
+		e.buf.InsertRune(o.offset, o.r)
+		e.setCursorFromOffset(o.offset + 1)
+	}
+}
+
+func (e *Editor) save() error {
+	err := os.WriteFile(e.filename, []byte(e.buf.String()), 0644)
 	if err == nil {
 		e.modified = false
 	}
 	return err
 }
 
+// saveAs writes the buffer to filename and adopts it as the editor's
+// filename, the way :w <path> does in a modal editor.
+func (e *Editor) saveAs(filename string) error {
+	if err := os.WriteFile(filename, []byte(e.buf.String()), 0644); err != nil {
+		return err
+	}
+	e.filename = filename
+	e.modified = false
+	return nil
+}
+
+// gotoLine moves the cursor to the start of the given 1-indexed line,
+// clamped to the document's bounds.
+func (e *Editor) gotoLine(n int) {
+	line := n - 1
+	if line < 0 {
+		line = 0
+	}
+	if last := e.buf.LineCount() - 1; line > last {
+		line = last
+	}
+	e.cursorY = line
+	e.cursorX = 0
+}
+
+// search moves the cursor to the first occurrence of query after the
+// current position, wrapping around to the start of the document
+// if nothing is found before the end. It's a no-op if query doesn't
+// appear anywhere, including an empty query.
+func (e *Editor) search(query string) {
+	q := []rune(query)
+	if len(q) == 0 {
+		return
+	}
+	lineCount := e.buf.LineCount()
+	for i := 0; i <= lineCount; i++ {
+		y := (e.cursorY + i) % lineCount
+		line := e.buf.Line(y)
+		from := 0
+		if i == 0 {
+			from = e.cursorX + 1
+		}
+		for x := from; x+len(q) <= len(line); x++ {
+			if runeSliceEqual(line[x:x+len(q)], q) {
+				e.cursorY = y
+				e.cursorX = x
+				return
+			}
+		}
+	}
+}
+
+// runeSliceEqual reports whether a and b hold the same runes.
+func runeSliceEqual(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const lineNumWidth = 4 // "%3d " gutter
+
+// styleFor maps a token kind to the SGR escape sequence render() uses
+// to color it. Plain tokens and unrecognized kinds keep the terminal's
+// default style.
+func styleFor(kind syntax.TokenKind) string {
+	switch kind {
+	case syntax.Keyword:
+		return "\033[34m" // blue
+	case syntax.String:
+		return "\033[32m" // green
+	case syntax.Comment:
+		return "\033[90m" // gray
+	case syntax.Number:
+		return "\033[35m" // magenta
+	default:
+		return ""
+	}
+}
+
 func (e *Editor) render() {
-	// Get terminal size
-	cmd := exec.Command("stty", "size")
-	cmd.Stdin = os.Stdin
-	out, _ := cmd.Output()
-	rows, _ := strconv.Atoi(strings.Split(string(out), " ")[0])
-	visibleLines := rows - 1 // Leave one line for status
+	width, height, err := e.term.Size()
+	if err != nil {
+		width, height = 80, 24
+	}
+	if sw, sh := e.screen.Size(); sw != width || sh != height {
+		e.screen.Resize(width, height)
+	}
+	visibleLines := height - 1 // Leave one line for status
+
+	lineCount := e.buf.LineCount()
 
 	// Calculate viewport
 	startLine := 0
-	if len(e.content) > visibleLines {
+	if lineCount > visibleLines {
 		startLine = e.cursorY - visibleLines/2
 		if startLine < 0 {
 			startLine = 0
 		}
-		if startLine > len(e.content)-visibleLines {
-			startLine = len(e.content) - visibleLines
+		if startLine > lineCount-visibleLines {
+			startLine = lineCount - visibleLines
 		}
 	}
 
-	// Clear screen
-	fmt.Print("\033[2J")
-	fmt.Print("\033[H")
+	// Find the lexer state entering the viewport so multi-line
+	// constructs (block comments, fenced code) render correctly after a
+	// scroll, without replaying every line from the top of the document.
+	state := e.lexStateBefore(startLine, lineCount)
 
-	// Render visible content
-	for i := 0; i < visibleLines && i+startLine < len(e.content); i++ {
+	// Render visible content into the back buffer
+	for i := 0; i < visibleLines && i+startLine < lineCount; i++ {
 		lineNum := i + startLine
-		line := e.content[lineNum]
-		fmt.Printf("\033[90m%3d \033[0m", lineNum+1)
-		fmt.Println(string(line))
+		line := e.buf.Line(lineNum)
+		e.screen.SetString(0, i, fmt.Sprintf("%3d ", lineNum+1))
+		e.screen.SetString(lineNumWidth, i, string(line))
+
+		var tokens []syntax.Token
+		tokens, state = e.lexCache.Tokenize(line, state)
+		for _, tok := range tokens {
+			if style := styleFor(tok.Kind); style != "" {
+				e.screen.SetStringStyled(lineNumWidth+tok.Start, i, string(line[tok.Start:tok.End]), style)
+			}
+		}
 	}
 
-	// Move cursor to position (accounting for line number width)
-	fmt.Printf("\033[%d;%dH", e.cursorY-startLine+1, e.cursorX+5)
+	// Position cursor (accounting for line number gutter)
+	e.screen.SetCursor(e.cursorX+lineNumWidth, e.cursorY-startLine)
+
+	e.screen.Flush(os.Stdout)
 }
 
-func cleanup() {
+func cleanup(tty *term.Terminal) {
 	// Reset terminal to normal mode
 	fmt.Print("\033[?25h") // Show cursor
 	fmt.Print("\033[0m")   // Reset all attributes
 	fmt.Print("\033[H")    // Move to home position
 	fmt.Print("\033[2J")   // Clear screen
+	tty.Restore()
 	keyboard.Close()
 }
 
@@ -160,23 +675,61 @@ func main() {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
-
 	// Initialize keyboard
 	if err := keyboard.Open(); err != nil {
 		fmt.Printf("Error opening keyboard: %v\n", err)
 		os.Exit(1)
 	}
 
+	tty, err := term.Open(int(os.Stdin.Fd()))
+	if err != nil {
+		fmt.Printf("Error opening terminal: %v\n", err)
+		os.Exit(1)
+	}
+	if err := editor.attachTerminal(tty); err != nil {
+		fmt.Printf("Error reading terminal size: %v\n", err)
+		os.Exit(1)
+	}
+	quit, stopSignals := tty.HandleSignals(editor.handleResize)
+	defer stopSignals()
+
 	// Set up proper terminal cleanup
-	defer cleanup()
+	defer cleanup(tty)
+
+	// Read keys off the tty on their own goroutine so the main loop can
+	// select between a keypress and quit, which is closed on
+	// SIGINT/SIGTERM: returning from main (rather than os.Exit) is what
+	// lets the deferred cleanup above actually run for a signaled quit.
+	type keyEvent struct {
+		char rune
+		key  keyboard.Key
+		err  error
+	}
+	keys := make(chan keyEvent)
+	go func() {
+		for {
+			char, key, err := keyboard.GetKey()
+			keys <- keyEvent{char, key, err}
+			if err != nil {
+				return
+			}
+		}
+	}()
 
 	for {
 		editor.render()
 
-		char, key, err := keyboard.GetKey()
-		if err != nil {
-			fmt.Printf("Error reading keyboard: %v\n", err)
-			os.Exit(1)
+		var char rune
+		var key keyboard.Key
+		select {
+		case <-quit:
+			return
+		case ev := <-keys:
+			char, key = ev.char, ev.key
+			if ev.err != nil {
+				fmt.Printf("Error reading keyboard: %v\n", ev.err)
+				os.Exit(1)
+			}
 		}
 
 		switch key {
@@ -184,60 +737,79 @@ func main() {
 			if !editor.modified {
 				return
 			}
+			editor.flushGroup()
 			// Ask to save if modified
 			fmt.Print("\033[2J")
 			fmt.Print("\033[H")
-			fmt.Print("File has unsaved changes. Save before quitting? (y/n): ")
-			var response string
 			for {
-				char, key, _ := keyboard.GetKey()
-				if key == keyboard.KeyEnter {
-					if response == "y" {
-						if err := editor.save(); err != nil {
-							fmt.Printf("Error saving: %v\n", err)
-							continue
-						}
-						return
-					} else if response == "n" {
-						return
-					}
-					// Invalid input, ask again
-					fmt.Print("\nPlease enter 'y' or 'n': ")
-					response = ""
-				} else if key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2 {
-					if len(response) > 0 {
-						response = response[:len(response)-1]
-						fmt.Print("\b \b") // Move back, clear character, move back again
+				response, err := editor.Prompt("File has unsaved changes. Save before quitting? (y/n): ", nil)
+				if err != nil {
+					// Cancelled; resume editing.
+					break
+				}
+				if response == "y" {
+					if err := editor.save(); err != nil {
+						fmt.Printf("Error saving: %v\n", err)
+						continue
 					}
-				} else if char != 0 {
-					response += string(char)
-					fmt.Print(string(char))
+					return
+				} else if response == "n" {
+					return
 				}
+				fmt.Print("Please enter 'y' or 'n'\n")
 			}
 		case keyboard.KeyCtrlS:
+			editor.flushGroup()
 			if err := editor.save(); err != nil {
 				fmt.Printf("Error saving: %v\n", err)
 			}
+		case keyboard.KeyCtrlZ:
+			editor.Undo()
+		case keyboard.KeyCtrlY:
+			editor.Redo()
+		case keyboard.KeyCtrlA:
+			editor.flushGroup()
+			if name, err := editor.Prompt("Save as: ", nil); err == nil && name != "" {
+				if err := editor.saveAs(name); err != nil {
+					fmt.Printf("Error saving: %v\n", err)
+				}
+			}
+		case keyboard.KeyCtrlG:
+			editor.flushGroup()
+			if input, err := editor.Prompt("Go to line: ", nil); err == nil {
+				if n, convErr := strconv.Atoi(strings.TrimSpace(input)); convErr == nil {
+					editor.gotoLine(n)
+				}
+			}
+		case keyboard.KeyCtrlF:
+			editor.flushGroup()
+			if query, err := editor.Prompt("Search: ", nil); err == nil {
+				editor.search(query)
+			}
 		case keyboard.KeyArrowLeft:
+			editor.flushGroup()
 			if editor.cursorX > 0 {
 				editor.cursorX--
 			}
 		case keyboard.KeyArrowRight:
-			if editor.cursorX < len(editor.content[editor.cursorY]) {
+			editor.flushGroup()
+			if editor.cursorX < len(editor.buf.Line(editor.cursorY)) {
 				editor.cursorX++
 			}
 		case keyboard.KeyArrowUp:
+			editor.flushGroup()
 			if editor.cursorY > 0 {
 				editor.cursorY--
-				if editor.cursorX > len(editor.content[editor.cursorY]) {
-					editor.cursorX = len(editor.content[editor.cursorY])
+				if editor.cursorX > len(editor.buf.Line(editor.cursorY)) {
+					editor.cursorX = len(editor.buf.Line(editor.cursorY))
 				}
 			}
 		case keyboard.KeyArrowDown:
-			if editor.cursorY < len(editor.content)-1 {
+			editor.flushGroup()
+			if editor.cursorY < editor.buf.LineCount()-1 {
 				editor.cursorY++
-				if editor.cursorX > len(editor.content[editor.cursorY]) {
-					editor.cursorX = len(editor.content[editor.cursorY])
+				if editor.cursorX > len(editor.buf.Line(editor.cursorY)) {
+					editor.cursorX = len(editor.buf.Line(editor.cursorY))
 				}
 			}
 		case keyboard.KeyEnter:
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DHMorse/dm/internal/buffer"
+	"github.com/DHMorse/dm/internal/syntax"
+)
+
+// replayFromTop is the naive approach lexStateBefore replaces:
+// re-tokenize every line from the top of the document on every call.
+// Tests use it as the ground truth lexStateBefore must match.
+func replayFromTop(e *Editor, startLine int) syntax.LexerState {
+	var state syntax.LexerState
+	for i := 0; i < startLine; i++ {
+		_, state = e.lexCache.Tokenize(e.buf.Line(i), state)
+	}
+	return state
+}
+
+func goFileWithBlockComment(lines int) string {
+	var sb strings.Builder
+	sb.WriteString("/* started here\n")
+	for i := 0; i < lines; i++ {
+		sb.WriteString("still inside the comment\n")
+	}
+	sb.WriteString("end */\n")
+	sb.WriteString("func tail() {}\n")
+	return sb.String()
+}
+
+func TestLexStateBeforeMatchesFullReplay(t *testing.T) {
+	content := goFileWithBlockComment(2 * lexCheckpointStride)
+	e := &Editor{
+		buf:      buffer.New([]rune(content)),
+		lexCache: syntax.NewCache(syntax.ForFile("x.go")),
+	}
+	lineCount := e.buf.LineCount()
+
+	for _, startLine := range []int{0, 1, lexCheckpointStride - 1, lexCheckpointStride, lexCheckpointStride + 5, 2 * lexCheckpointStride} {
+		want := replayFromTop(e, startLine)
+		got := e.lexStateBefore(startLine, lineCount)
+		if got != want {
+			t.Errorf("lexStateBefore(%d) = %v, want %v", startLine, got, want)
+		}
+	}
+}
+
+func TestLexStateBeforeMatchesReplayAfterInvalidatingEdit(t *testing.T) {
+	content := goFileWithBlockComment(2 * lexCheckpointStride)
+	e := &Editor{
+		buf:      buffer.New([]rune(content)),
+		lexCache: syntax.NewCache(syntax.ForFile("x.go")),
+	}
+	deep := 2 * lexCheckpointStride
+
+	// Prime the checkpoint cache.
+	e.lexStateBefore(deep, e.buf.LineCount())
+	if len(e.lexCheckpoints) < 2 {
+		t.Fatalf("lexCheckpoints len = %d, want at least 2 after priming", len(e.lexCheckpoints))
+	}
+
+	// An edit on line 0 invalidates every checkpoint after it...
+	e.cursorY, e.cursorX = 0, 0
+	e.insertRune('x')
+	if len(e.lexCheckpoints) != 1 {
+		t.Fatalf("lexCheckpoints len after edit on line 0 = %d, want 1", len(e.lexCheckpoints))
+	}
+
+	// ...but lexStateBefore still agrees with a full replay afterward.
+	want := replayFromTop(e, deep)
+	got := e.lexStateBefore(deep, e.buf.LineCount())
+	if got != want {
+		t.Errorf("lexStateBefore(%d) after edit = %v, want %v", deep, got, want)
+	}
+}
+
+func TestInvalidateLexCheckpointsKeepsCheckpointsBeforeEditLine(t *testing.T) {
+	e := &Editor{lexCheckpoints: []syntax.LexerState{0, 1, 2, 3}}
+	// Checkpoint i covers line i*lexCheckpointStride; an edit on line
+	// 2*stride+5 shouldn't disturb checkpoints 0..2.
+	e.invalidateLexCheckpoints(2*lexCheckpointStride + 5)
+	if len(e.lexCheckpoints) != 3 {
+		t.Fatalf("lexCheckpoints len = %d, want 3", len(e.lexCheckpoints))
+	}
+}
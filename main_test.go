@@ -0,0 +1,186 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DHMorse/dm/internal/buffer"
+)
+
+// newTestEditor builds an Editor around an empty buffer, with no
+// terminal or history wiring, so Undo/Redo can be exercised without a
+// TTY.
+func newTestEditor() *Editor {
+	return &Editor{buf: buffer.New(nil)}
+}
+
+func TestUndoRedoSingleInsert(t *testing.T) {
+	e := newTestEditor()
+	e.insertRune('a')
+	if got := e.buf.String(); got != "a" {
+		t.Fatalf("after insert: got %q, want %q", got, "a")
+	}
+
+	if !e.Undo() {
+		t.Fatal("Undo() = false, want true")
+	}
+	if got := e.buf.String(); got != "" {
+		t.Fatalf("after undo: got %q, want empty", got)
+	}
+	if e.Undo() {
+		t.Fatal("Undo() on empty stack = true, want false")
+	}
+
+	if !e.Redo() {
+		t.Fatal("Redo() = false, want true")
+	}
+	if got := e.buf.String(); got != "a" {
+		t.Fatalf("after redo: got %q, want %q", got, "a")
+	}
+	if e.Redo() {
+		t.Fatal("Redo() on empty stack = true, want false")
+	}
+}
+
+func TestConsecutiveInsertsGroupIntoOneUndo(t *testing.T) {
+	e := newTestEditor()
+	for _, r := range "abc" {
+		e.insertRune(r)
+	}
+	if got := e.buf.String(); got != "abc" {
+		t.Fatalf("after inserts: got %q, want %q", got, "abc")
+	}
+
+	if !e.Undo() {
+		t.Fatal("Undo() = false, want true")
+	}
+	if got := e.buf.String(); got != "" {
+		t.Fatalf("after one undo: got %q, want empty (whole run should undo together)", got)
+	}
+}
+
+func TestNewlineStartsNewGroup(t *testing.T) {
+	e := newTestEditor()
+	e.insertRune('a')
+	e.insertNewline()
+	e.insertRune('b')
+
+	if got := e.buf.String(); got != "a\nb" {
+		t.Fatalf("after inserts: got %q, want %q", got, "a\nb")
+	}
+
+	// The 'a' run is flushed into its own group as soon as the newline
+	// arrives; 'b' folds into the newline's group since it's a plain
+	// insert within the idle window, leaving two undoable groups.
+	e.Undo()
+	if got := e.buf.String(); got != "a" {
+		t.Fatalf("after first undo: got %q, want %q", got, "a")
+	}
+	e.Undo()
+	if got := e.buf.String(); got != "" {
+		t.Fatalf("after second undo: got %q, want empty", got)
+	}
+}
+
+func TestGroupTimeoutEndsGroup(t *testing.T) {
+	e := newTestEditor()
+	e.insertRune('a')
+	// Simulate the ~1s idle timeout without actually sleeping the test.
+	e.groupDeadline = time.Now().Add(-time.Millisecond)
+	e.insertRune('b')
+
+	if got := e.buf.String(); got != "ab" {
+		t.Fatalf("after inserts: got %q, want %q", got, "ab")
+	}
+	e.Undo()
+	if got := e.buf.String(); got != "a" {
+		t.Fatalf("after first undo: got %q, want %q", got, "a")
+	}
+	e.Undo()
+	if got := e.buf.String(); got != "" {
+		t.Fatalf("after second undo: got %q, want empty", got)
+	}
+}
+
+func TestBackspaceIsUndoable(t *testing.T) {
+	e := newTestEditor()
+	for _, r := range "hi" {
+		e.insertRune(r)
+	}
+	e.flushGroup()
+	e.backspace()
+	if got := e.buf.String(); got != "h" {
+		t.Fatalf("after backspace: got %q, want %q", got, "h")
+	}
+	e.Undo()
+	if got := e.buf.String(); got != "hi" {
+		t.Fatalf("after undo: got %q, want %q", got, "hi")
+	}
+}
+
+func TestUndoStackBounded(t *testing.T) {
+	e := newTestEditor()
+	for i := 0; i < maxUndoOps+10; i++ {
+		e.insertRune('x')
+		e.flushGroup()
+	}
+	if len(e.undoStack) != maxUndoOps {
+		t.Fatalf("undoStack len = %d, want %d", len(e.undoStack), maxUndoOps)
+	}
+}
+
+func TestGotoLineClampsToDocument(t *testing.T) {
+	e := &Editor{buf: buffer.New([]rune("one\ntwo\nthree\n"))}
+
+	e.gotoLine(2)
+	if e.cursorY != 1 || e.cursorX != 0 {
+		t.Fatalf("gotoLine(2): cursor = (%d,%d), want (1,0)", e.cursorY, e.cursorX)
+	}
+
+	e.gotoLine(100)
+	if want := e.buf.LineCount() - 1; e.cursorY != want {
+		t.Fatalf("gotoLine(100): cursorY = %d, want %d (clamped to last line)", e.cursorY, want)
+	}
+
+	e.gotoLine(0)
+	if e.cursorY != 0 {
+		t.Fatalf("gotoLine(0): cursorY = %d, want 0 (clamped to first line)", e.cursorY)
+	}
+}
+
+func TestSearchFindsAndWrapsAroundDocument(t *testing.T) {
+	e := &Editor{buf: buffer.New([]rune("alpha\nbeta\ngamma beta\n"))}
+
+	e.search("beta")
+	if e.cursorY != 1 || e.cursorX != 0 {
+		t.Fatalf("first search: cursor = (%d,%d), want (1,0)", e.cursorY, e.cursorX)
+	}
+
+	e.search("beta")
+	if e.cursorY != 2 || e.cursorX != 6 {
+		t.Fatalf("second search: cursor = (%d,%d), want (2,6)", e.cursorY, e.cursorX)
+	}
+
+	e.search("beta")
+	if e.cursorY != 1 || e.cursorX != 0 {
+		t.Fatalf("wraparound search: cursor = (%d,%d), want (1,0)", e.cursorY, e.cursorX)
+	}
+
+	e.search("nope")
+	if e.cursorY != 1 || e.cursorX != 0 {
+		t.Fatalf("no-match search moved the cursor to (%d,%d)", e.cursorY, e.cursorX)
+	}
+}
+
+func TestEditAfterUndoClearsRedo(t *testing.T) {
+	e := newTestEditor()
+	e.insertRune('a')
+	e.Undo()
+	if len(e.redoStack) != 1 {
+		t.Fatalf("redoStack len = %d, want 1", len(e.redoStack))
+	}
+	e.insertRune('b')
+	if len(e.redoStack) != 0 {
+		t.Fatalf("redoStack len = %d, want 0 after a new edit", len(e.redoStack))
+	}
+}